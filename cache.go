@@ -0,0 +1,220 @@
+package main
+
+import (
+    "bufio"
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "sync"
+)
+
+const cacheDirName  string = ".niceobjects"
+const cacheFileName string = "cache.rec"
+
+// cacheEntry records enough about one resource to tell, on a later run,
+// whether it needs re-translating: its source file's mtime/size/digest,
+// and the digest of the last file we generated from it.
+type cacheEntry struct {
+    Path      string
+    ModTime   int64
+    Size      int64
+    SrcDigest string
+    DstDigest string
+}
+
+// Cache is a recfile-style ("one stanza per resource") on-disk cache
+// keyed by source path, used to skip re-translating resources that
+// haven't changed since the last run.
+type Cache struct {
+    mu      sync.Mutex
+    path    string
+    entries map[string]cacheEntry
+}
+
+func cacheFilePath (projectDir string) string {
+    return filepath.Join(projectDir, cacheDirName, cacheFileName)
+}
+
+// LoadCache reads the cache file for projectDir, returning an empty Cache
+// if it doesn't exist yet. Lines it can't parse are skipped, so a stanza
+// left incomplete by a partial previous run is kept without its missing
+// fields and fails UpToDate's checks.
+func LoadCache (projectDir string) *Cache {
+    c := &Cache{
+        path:    cacheFilePath(projectDir),
+        entries: make(map[string]cacheEntry),
+    }
+
+    f, err := os.Open(c.path)
+    if err != nil {
+        return c
+    }
+    defer f.Close()
+
+    var cur cacheEntry
+    scan := bufio.NewScanner(f)
+    for scan.Scan() {
+        line := scan.Text()
+        if line == "" {
+            if cur.Path != "" {
+                c.entries[cur.Path] = cur
+            }
+            cur = cacheEntry{}
+            continue
+        }
+
+        parts := strings.SplitN(line, ": ", 2)
+        if len(parts) != 2 {
+            continue
+        }
+        switch parts[0] {
+        case "path":
+            cur.Path = parts[1]
+        case "mtime":
+            cur.ModTime, _ = strconv.ParseInt(parts[1], 10, 64)
+        case "size":
+            cur.Size, _ = strconv.ParseInt(parts[1], 10, 64)
+        case "src_digest":
+            cur.SrcDigest = parts[1]
+        case "dst_digest":
+            cur.DstDigest = parts[1]
+        }
+    }
+    if cur.Path != "" {
+        c.entries[cur.Path] = cur
+    }
+
+    return c
+}
+
+// Save writes the cache out as a recfile, creating the containing
+// .niceobjects directory if necessary.
+func (c *Cache) Save () error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    if err := os.MkdirAll(filepath.Dir(c.path), os.ModePerm); err != nil {
+        return err
+    }
+
+    f, err := os.Create(c.path)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+
+    w := bufio.NewWriter(f)
+    for _, e := range c.entries {
+        fmt.Fprintf(w, "path: %v\n", e.Path)
+        fmt.Fprintf(w, "mtime: %v\n", e.ModTime)
+        fmt.Fprintf(w, "size: %v\n", e.Size)
+        fmt.Fprintf(w, "src_digest: %v\n", e.SrcDigest)
+        fmt.Fprintf(w, "dst_digest: %v\n", e.DstDigest)
+        fmt.Fprintln(w)
+    }
+    return w.Flush()
+}
+
+// Wipe clears every entry and rewrites an empty cache file, for the
+// --rebuild flag and "rebuild" stdin command.
+func (c *Cache) Wipe () error {
+    c.mu.Lock()
+    c.entries = make(map[string]cacheEntry)
+    c.mu.Unlock()
+    return c.Save()
+}
+
+func fileDigest (path string) (string, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return "", err
+    }
+    defer f.Close()
+
+    h := sha256.New()
+    if _, err := io.Copy(h, f); err != nil {
+        return "", err
+    }
+    return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// UpToDate reports whether srcPath is unchanged since it was last
+// recorded against dstPath, so translation can be skipped. It also
+// guards against the common fsnotify false positive where an editor
+// rewrites a file with identical bytes.
+func (c *Cache) UpToDate (srcPath string, dstPath string) bool {
+    info, err := os.Stat(srcPath)
+    if err != nil {
+        return false
+    }
+
+    c.mu.Lock()
+    e, ok := c.entries[srcPath]
+    c.mu.Unlock()
+    // A rewrite with identical bytes changes the mtime but not the
+    // digests, so only the size is used as a quick rejection here.
+    if !ok || e.Size != info.Size() {
+        return false
+    }
+
+    if _, err := os.Stat(dstPath); err != nil {
+        return false
+    }
+
+    srcDigest, err := fileDigest(srcPath)
+    if err != nil || srcDigest != e.SrcDigest {
+        return false
+    }
+    dstDigest, err := fileDigest(dstPath)
+    if err != nil || dstDigest != e.DstDigest {
+        return false
+    }
+
+    return true
+}
+
+// Record digests srcPath and dstPath, stores the result, and saves the
+// cache to disk, to be consulted by a later UpToDate call. For recording
+// many resources at once (e.g. the initial scan), use stage in a loop
+// and Save once afterwards instead -- Record's per-call Save turns an
+// O(n) scan into O(n^2) disk I/O.
+func (c *Cache) Record (srcPath string, dstPath string) error {
+    if err := c.stage(srcPath, dstPath); err != nil {
+        return err
+    }
+    return c.Save()
+}
+
+// stage is Record without the Save, for batching many updates into one
+// write.
+func (c *Cache) stage (srcPath string, dstPath string) error {
+    info, err := os.Stat(srcPath)
+    if err != nil {
+        return err
+    }
+    srcDigest, err := fileDigest(srcPath)
+    if err != nil {
+        return err
+    }
+    dstDigest, err := fileDigest(dstPath)
+    if err != nil {
+        return err
+    }
+
+    c.mu.Lock()
+    c.entries[srcPath] = cacheEntry{
+        Path:      srcPath,
+        ModTime:   info.ModTime().Unix(),
+        Size:      info.Size(),
+        SrcDigest: srcDigest,
+        DstDigest: dstDigest,
+    }
+    c.mu.Unlock()
+
+    return nil
+}
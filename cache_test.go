@@ -0,0 +1,85 @@
+package main
+
+import (
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func TestCacheUpToDateRoundTrip (t *testing.T) {
+    dir, err := ioutil.TempDir("", "niceobjects-cache-test")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+
+    src := filepath.Join(dir, "oPlayer.object.gmx")
+    dst := filepath.Join(dir, "oPlayer.gmo")
+    if err := ioutil.WriteFile(src, []byte("src contents"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    if err := ioutil.WriteFile(dst, []byte("dst contents"), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    c := LoadCache(dir)
+    if c.UpToDate(src, dst) {
+        t.Error("expected a never-recorded resource to not be up to date")
+    }
+
+    if err := c.Record(src, dst); err != nil {
+        t.Fatal(err)
+    }
+    if !c.UpToDate(src, dst) {
+        t.Error("expected the resource to be up to date right after Record")
+    }
+
+    if err := ioutil.WriteFile(src, []byte("changed"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    if c.UpToDate(src, dst) {
+        t.Error("expected a changed source file to no longer be up to date")
+    }
+}
+
+func TestCacheStageThenSingleSave (t *testing.T) {
+    dir, err := ioutil.TempDir("", "niceobjects-cache-test")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+
+    var srcs, dsts []string
+    for i := 0; i < 5; i++ {
+        src := filepath.Join(dir, string(rune('a'+i))+".object.gmx")
+        dst := filepath.Join(dir, string(rune('a'+i))+".gmo")
+        ioutil.WriteFile(src, []byte("src"), 0644)
+        ioutil.WriteFile(dst, []byte("dst"), 0644)
+        srcs = append(srcs, src)
+        dsts = append(dsts, dst)
+    }
+
+    c := LoadCache(dir)
+    for i := range srcs {
+        if err := c.stage(srcs[i], dsts[i]); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    // stage alone must not have written the cache file yet.
+    if _, err := os.Stat(cacheFilePath(dir)); err == nil {
+        t.Error("expected stage to not write the cache file on its own")
+    }
+
+    if err := c.Save(); err != nil {
+        t.Fatal(err)
+    }
+
+    reloaded := LoadCache(dir)
+    for i := range srcs {
+        if !reloaded.UpToDate(srcs[i], dsts[i]) {
+            t.Errorf("expected %v to be up to date after a single Save", srcs[i])
+        }
+    }
+}
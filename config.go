@@ -0,0 +1,197 @@
+package main
+
+import (
+    "fmt"
+    "io/ioutil"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "gopkg.in/yaml.v2"
+)
+
+const configFileName string = "niceobjects.yaml"
+
+// ScriptHook is a user command run after a successful translation.
+type ScriptHook struct {
+    Event   string `yaml:"event"`
+    Command string `yaml:"command"`
+}
+
+// Config holds everything that used to be hardcoded in main(): which
+// subdirectories/extensions to watch, what to ignore, and what to run
+// after a translation happens.
+type Config struct {
+    Include        []string     `yaml:"include"`
+    Ignore         []string     `yaml:"ignore"`
+    Extensions     []string     `yaml:"extensions"`
+    Scripts        []ScriptHook `yaml:"scripts"`
+    QuietPeriodMS  int          `yaml:"quiet_period_ms"`
+}
+
+var config Config
+
+// LoadConfig looks for niceobjects.yaml first in projectDir, then next to
+// the running executable, and returns a zero-value Config (no include,
+// ignore, or scripts) if neither is found.
+func LoadConfig (projectDir string) (Config, error) {
+    candidates := []string{filepath.Join(projectDir, configFileName)}
+
+    exe, err := os.Executable()
+    if err == nil {
+        candidates = append(candidates, filepath.Join(filepath.Dir(exe), configFileName))
+    }
+
+    for _, path := range candidates {
+        data, err := ioutil.ReadFile(path)
+        if err != nil {
+            if os.IsNotExist(err) {
+                continue
+            }
+            return Config{}, err
+        }
+
+        var c Config
+        if err := yaml.Unmarshal(data, &c); err != nil {
+            return Config{}, err
+        }
+        return c, nil
+    }
+
+    return Config{}, nil
+}
+
+// isIgnored reports whether path should be skipped: either it matches one
+// of the configured ignore globs, or an include list is configured and
+// path matches none of its patterns. Ignore globs are matched against the
+// path's base name only; include patterns are matched against both the
+// base name and the path relative to projectDir (see matchesInclude).
+func isIgnored (path string) bool {
+    base := filepath.Base(path)
+    for _, pattern := range config.Ignore {
+        if ok, _ := filepath.Match(pattern, base); ok {
+            return true
+        }
+    }
+
+    if len(config.Include) > 0 && !matchesInclude(path) {
+        return true
+    }
+
+    return false
+}
+
+// matchesInclude reports whether path matches one of config.Include's
+// patterns, checked against both the base name (e.g. "enemies") and the
+// path relative to projectDir (e.g. "objects/enemies/*").
+func matchesInclude (path string) bool {
+    base := filepath.Base(path)
+    rel, err := filepath.Rel(projectDir, path)
+    if err != nil {
+        rel = path
+    }
+
+    for _, pattern := range config.Include {
+        if ok, _ := filepath.Match(pattern, base); ok {
+            return true
+        }
+        if ok, _ := filepath.Match(pattern, rel); ok {
+            return true
+        }
+    }
+    return false
+}
+
+// isIgnoredDir reports whether addRecursive should prune dir entirely:
+// either it matches an ignore glob, or an include list is configured and
+// no include pattern could possibly match anything under dir. Unlike
+// isIgnored, this can't just run matchesInclude against dir's own path --
+// a pattern like "objects/enemies/*" has one more path segment than the
+// directory "objects/enemies", so filepath.Match (which requires equal
+// segment counts) would never match the directory itself, wrongly
+// pruning it and everything beneath it.
+func isIgnoredDir (path string) bool {
+    base := filepath.Base(path)
+    for _, pattern := range config.Ignore {
+        if ok, _ := filepath.Match(pattern, base); ok {
+            return true
+        }
+    }
+
+    if len(config.Include) > 0 && !includeMayMatchUnder(path) {
+        return true
+    }
+
+    return false
+}
+
+// includeMayMatchUnder reports whether some config.Include pattern could
+// match a file under dir, by comparing path segments one at a time
+// instead of requiring a whole-pattern match against dir itself. Segments
+// beyond whichever of dir/pattern runs out first are left unconstrained,
+// since dir may still have matching descendants (pattern longer than dir)
+// or the pattern may already be satisfied by dir's own prefix (dir longer
+// than pattern, e.g. "objects/*" against "objects/enemies/boss").
+// Segments are compared from the start of dir's path relative to
+// projectDir, so a bare-name pattern like "enemies" is compared with that
+// path's first segment (e.g. "objects"), not with dir's own name.
+func includeMayMatchUnder (dir string) bool {
+    rel, err := filepath.Rel(projectDir, dir)
+    if err != nil {
+        rel = dir
+    }
+    relSegs := strings.Split(rel, string(filepath.Separator))
+
+    for _, pattern := range config.Include {
+        patSegs := strings.Split(pattern, "/")
+
+        n := len(relSegs)
+        if len(patSegs) < n {
+            n = len(patSegs)
+        }
+
+        match := true
+        for i := 0; i < n; i++ {
+            if ok, _ := filepath.Match(patSegs[i], relSegs[i]); !ok {
+                match = false
+                break
+            }
+        }
+        if match {
+            return true
+        }
+    }
+    return false
+}
+
+// isIncludedExt reports whether ext (as returned by filepath.Ext) is one
+// of the extra script extensions declared in the config, e.g. ".shader"
+// or ".gml" header variants.
+func isIncludedExt (ext string) bool {
+    for _, e := range config.Extensions {
+        if e == ext {
+            return true
+        }
+    }
+    return false
+}
+
+// runScriptHooks runs every configured hook whose Event matches event
+// ("human_write" or "gm_write"), appending name as an argument to the
+// configured command.
+func runScriptHooks (event string, name string) {
+    for _, hook := range config.Scripts {
+        if hook.Event != event {
+            continue
+        }
+        fields := append(strings.Fields(hook.Command), name)
+        cmd := exec.Command(fields[0], fields[1:]...)
+        cmd.Dir = projectDir
+        if err := cmd.Run(); err != nil {
+            fmt.Printf("[%v] Script hook %v failed: %v\n",
+                    time.Now().Format("15:04:05"), hook.Command, err)
+        }
+    }
+}
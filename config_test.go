@@ -0,0 +1,54 @@
+package main
+
+import "testing"
+
+func TestIsIgnoredWithIgnoreGlobs (t *testing.T) {
+    config = Config{Ignore: []string{"*.bak"}}
+    defer func () { config = Config{} }()
+
+    if !isIgnored("/proj/objects/foo.bak") {
+        t.Error("expected foo.bak to be ignored")
+    }
+    if isIgnored("/proj/objects/foo.object.gmx") {
+        t.Error("expected foo.object.gmx to not be ignored")
+    }
+}
+
+func TestIsIgnoredWithIncludeList (t *testing.T) {
+    projectDir = "/proj/"
+    config = Config{Include: []string{"objects/enemies/*"}}
+    defer func () { config = Config{}; projectDir = "" }()
+
+    if isIgnored("/proj/objects/enemies/oSlime.object.gmx") {
+        t.Error("expected a file under the included directory to not be ignored")
+    }
+    if !isIgnored("/proj/objects/ui/oButton.object.gmx") {
+        t.Error("expected a file outside the include list to be ignored")
+    }
+}
+
+func TestIsIgnoredWithNoInclude (t *testing.T) {
+    projectDir = "/proj/"
+    config = Config{}
+    defer func () { projectDir = "" }()
+
+    if isIgnored("/proj/objects/anything.object.gmx") {
+        t.Error("expected no include list to mean everything is included")
+    }
+}
+
+func TestIsIgnoredDirWithIncludeList (t *testing.T) {
+    projectDir = "/proj/"
+    config = Config{Include: []string{"objects/enemies/*"}}
+    defer func () { config = Config{}; projectDir = "" }()
+
+    if isIgnoredDir("/proj/objects") {
+        t.Error("expected a directory on the way to an included pattern to not be pruned")
+    }
+    if isIgnoredDir("/proj/objects/enemies") {
+        t.Error("expected the directory the include pattern lives under to not be pruned, even though filepath.Match needs an extra path segment to match it directly")
+    }
+    if !isIgnoredDir("/proj/objects/ui") {
+        t.Error("expected a directory that can't lead to any include pattern to be pruned")
+    }
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+    "sync"
+    "time"
+)
+
+// Event is one translation/copy outcome, as streamed over the /events
+// WebSocket. Stdout gets the logger's text lines, not Events.
+type Event struct {
+    Time      time.Time `json:"time"`
+    Direction string    `json:"direction"` // "human_to_gm" or "gm_to_human"
+    Kind      string    `json:"kind"`      // "object" or "script"
+    Name      string    `json:"name"`
+    Error     string    `json:"error,omitempty"`
+}
+
+var eventSubs = struct {
+    mu   sync.Mutex
+    subs map[chan Event]bool
+}{subs: make(map[chan Event]bool)}
+
+// subscribeEvents registers a channel that receives every future event,
+// for the /events WebSocket handler.
+func subscribeEvents () chan Event {
+    ch := make(chan Event, 16)
+    eventSubs.mu.Lock()
+    eventSubs.subs[ch] = true
+    eventSubs.mu.Unlock()
+    return ch
+}
+
+func unsubscribeEvents (ch chan Event) {
+    eventSubs.mu.Lock()
+    delete(eventSubs.subs, ch)
+    eventSubs.mu.Unlock()
+    close(ch)
+}
+
+// publishEvent fans e out to every subscriber, dropping it for any
+// subscriber whose buffer is full rather than blocking translation.
+func publishEvent (e Event) {
+    e.Time = time.Now()
+
+    eventSubs.mu.Lock()
+    defer eventSubs.mu.Unlock()
+    for ch := range eventSubs.subs {
+        select {
+        case ch <- e:
+        default:
+        }
+    }
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "time"
+)
+
+type logLevel int
+
+const (
+    LevelDebug logLevel = iota
+    LevelInfo
+    LevelWarn
+    LevelError
+)
+
+func (l logLevel) String () string {
+    switch l {
+    case LevelDebug:
+        return "debug"
+    case LevelInfo:
+        return "info"
+    case LevelWarn:
+        return "warn"
+    case LevelError:
+        return "error"
+    }
+    return "unknown"
+}
+
+type logRecord struct {
+    Time  string `json:"time"`
+    Level string `json:"level"`
+    Msg   string `json:"msg"`
+}
+
+const sessionLogName string = ".niceobjects.log"
+
+// Logger writes leveled, timestamped lines to stdout and to a rotating
+// session log file under humanDir, optionally as JSON (for the HTTP/
+// editor integration) and with TAI64N timestamps (for grepping logs
+// across timezone changes).
+type Logger struct {
+    out      io.Writer
+    file     io.Writer
+    minLevel logLevel
+    json     bool
+    tai64n   bool
+}
+
+var logger *Logger
+
+// InitLogger opens <humanDir>/.niceobjects.log, rotating any log left
+// over from the previous session to a .1 suffix, and installs the
+// package-level logger used by log.Printf-style helpers below.
+func InitLogger (humanDir string, verbose bool, jsonOutput bool, tai64n bool) (*Logger, error) {
+    logPath := filepath.Join(humanDir, sessionLogName)
+    if _, err := os.Stat(logPath); err == nil {
+        os.Rename(logPath, logPath+".1")
+    }
+
+    f, err := os.Create(logPath)
+    if err != nil {
+        return nil, err
+    }
+
+    minLevel := LevelInfo
+    if verbose {
+        minLevel = LevelDebug
+    }
+
+    logger = &Logger{
+        out:      os.Stdout,
+        file:     f,
+        minLevel: minLevel,
+        json:     jsonOutput,
+        tai64n:   tai64n,
+    }
+    return logger, nil
+}
+
+func (l *Logger) timestamp () string {
+    if l.tai64n {
+        return tai64nNow()
+    }
+    return time.Now().Format("15:04:05")
+}
+
+func (l *Logger) log (level logLevel, format string, args ...interface{}) {
+    if level < l.minLevel {
+        return
+    }
+    msg := fmt.Sprintf(format, args...)
+
+    if l.json {
+        data, _ := json.Marshal(logRecord{
+            Time:  l.timestamp(),
+            Level: level.String(),
+            Msg:   msg,
+        })
+        fmt.Fprintln(l.out, string(data))
+        fmt.Fprintln(l.file, string(data))
+        return
+    }
+
+    line := fmt.Sprintf("[%v] %v", l.timestamp(), msg)
+    fmt.Fprintln(l.out, line)
+    fmt.Fprintln(l.file, line)
+}
+
+func (l *Logger) Debug (format string, args ...interface{}) { l.log(LevelDebug, format, args...) }
+func (l *Logger) Info  (format string, args ...interface{}) { l.log(LevelInfo, format, args...) }
+func (l *Logger) Warn  (format string, args ...interface{}) { l.log(LevelWarn, format, args...) }
+func (l *Logger) Error (format string, args ...interface{}) { l.log(LevelError, format, args...) }
+
+// tai64nNow formats the current time as TAI64N, the same external format
+// daemontools/multilog use, for users who grep logs across timezones.
+// Like those tools, this doesn't apply a live leap-second table.
+func tai64nNow () string {
+    const taiEpochOffset uint64 = 1<<62 + 10
+    now := time.Now().UTC()
+    secs := uint64(now.Unix()) + taiEpochOffset
+    return fmt.Sprintf("@%016x%08x", secs, now.Nanosecond())
+}
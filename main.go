@@ -8,7 +8,6 @@ import (
     "path/filepath"
     "strings"
     "github.com/fsnotify/fsnotify"
-    "time"
     "syscall"
     "github.com/sqweek/dialog"
 )
@@ -18,19 +17,20 @@ var projectPath  string
 var projectDir   string
 var gmObjectsDir string
 var gmScriptsDir string
-
-// "Reverb" refers to translations causing file Write events back and forth
-// between the GM and human folders.
-const reverbSpacing time.Duration = 1 * time.Second
-const dedupSpacing  time.Duration = 100 * time.Millisecond
-var gmChanged       time.Time
-var humanChanged    time.Time
-var lastGMFileChanged    string
-var lastHumanFileChanged string
+var cache        *Cache
+var activeTranslator Translator
 
 const usage string = `Usage:
-gm_txt.exe [project_file]
+gm_txt.exe [project_file] [--rebuild] [--listen addr] [--verbose] [--json] [--tai64n]
 If you provide no arguments, it'll open a file picker dialog.
+--rebuild ignores the translation cache and re-translates everything.
+--listen starts an HTTP/WebSocket control server on addr (e.g. :8080).
+  A bare ":port" binds loopback only (127.0.0.1); pass a full address
+  like 0.0.0.0:8080 to expose it on the network. There's no
+  authentication, so anyone who can reach it can trigger translations.
+--verbose logs every suppressed event (ignored/self-written files) too.
+--json logs as JSON lines instead of "[hh:mm:ss] message".
+--tai64n logs TAI64N timestamps instead of local wall-clock time.
 `
 
 func main () {
@@ -38,8 +38,34 @@ func main () {
 
     // Parse arguments
 
+    // --rebuild, --listen, --verbose, --json, and --tai64n may appear
+    // anywhere among the arguments.
+    rebuild := false
+    verbose := false
+    jsonLog := false
+    tai64n  := false
+    listenAddr := ""
+    var argv []string
+    for i := 1; i < len(os.Args); i++ {
+        a := os.Args[i]
+        if a == "--rebuild" {
+            rebuild = true
+        } else if a == "--verbose" {
+            verbose = true
+        } else if a == "--json" {
+            jsonLog = true
+        } else if a == "--tai64n" {
+            tai64n = true
+        } else if a == "--listen" && i+1 < len(os.Args) {
+            listenAddr = os.Args[i+1]
+            i++
+        } else {
+            argv = append(argv, a)
+        }
+    }
+
     // No args opens windows file picker for project path
-    if len(os.Args) < 2 {
+    if len(argv) < 1 {
         var err error
         projectPath, err = dialog.File().
                 Title("Select GMS project file").
@@ -51,13 +77,13 @@ func main () {
             return
         }
 
-    } else if len(os.Args) == 2 && os.Args[1] == "--help" {
+    } else if len(argv) == 1 && argv[0] == "--help" {
         fmt.Println(usage)
         return
 
     // One arg (non-help) specifies project path
-    } else if len(os.Args) == 2 {
-        projectPath = os.Args[1]
+    } else if len(argv) == 1 {
+        projectPath = argv[0]
 
     } else {
         fmt.Println(usage)
@@ -88,6 +114,30 @@ func main () {
     gmScriptsDir = filepath.Join(projectDir, "scripts")
     humanDir     = filepath.Join(projectDir, "gm_txt")
 
+    // Load niceobjects.yaml, if present, for include/ignore/extension/
+    // script-hook configuration.
+
+    config, err = LoadConfig(projectDir)
+    if err != nil {
+        fmt.Printf("Error loading %v: %v\n", configFileName, err)
+        return
+    }
+    applyQuietPeriodOverride(config)
+
+    // Load the incremental translation cache.
+
+    cache = LoadCache(projectDir)
+    if rebuild {
+        if err := cache.Wipe(); err != nil {
+            fmt.Printf("Error wiping cache: %v\n", err)
+            return
+        }
+    }
+
+    // Pick a Translator backend for this project's format.
+
+    activeTranslator = DetectTranslator(projectPath)
+
     // Start listening for SIGINT (Ctrl-C)
 
     sigchan := make(chan os.Signal, 2)
@@ -101,53 +151,46 @@ func main () {
         return
     }
 
-    // Translate all GM objects.
-
-    // implements filepath.WalkFunc
-    f := func (path string, info os.FileInfo, err error) error {
-        // Skip directories and extraneous files.
-        if info.IsDir() || !strings.HasSuffix(path, ".object.gmx") {
-            return nil
-        }
-
-        // Compute translated file path.
-        resourceName := strings.TrimSuffix(filepath.Base(path), ".object.gmx")
-        destPath := filepath.Join(humanDir, resourceName+".gmo")
+    // Open the rotating session log.
 
-        // Translate.
-        err = GMObjectFileToHumanObjectFile(path, destPath)
-        if err != nil {
-            fmt.Printf("Error initially translating %v: %v\n",
-                    resourceName, err)
-            return err
-        }
-        return err
+    if _, err := InitLogger(humanDir, verbose, jsonLog, tai64n); err != nil {
+        fmt.Printf("Error opening session log: %v\n", err)
+        return
     }
 
-    err = filepath.Walk(gmObjectsDir, f)
+    // Translate/copy every existing resource.
+
+    pairs, err := activeTranslator.InitialScan(projectDir)
     if err != nil {
-        fmt.Printf("Error during initial translation of all GM objects "+
-                "to human objects: %v\n", err)
+        fmt.Printf("Error during initial scan of %v: %v\n", projectDir, err)
         return
     }
 
-    // Copy over all GM scripts.
-
-    // implements filepath.WalkFunc
-    f = func (path string, info os.FileInfo, err error) error {
-        // Skip directories and extraneous files.
-        if info.IsDir() || filepath.Ext(path) != ".gml" {
-            return nil
+    cacheDirty := false
+    for _, pair := range pairs {
+        // Skip resources the cache says are unchanged and whose
+        // human-side file is still present. The gm_txt directory is
+        // removed when main exits on a signal, so after such an exit
+        // every resource is translated again.
+        if cache.UpToDate(pair.Src, pair.Dst) {
+            continue
         }
 
-        destPath := filepath.Join(humanDir, filepath.Base(path))
-        return cp(destPath, path)
+        if err := activeTranslator.GMToHuman(pair.Src, pair.Dst); err != nil {
+            fmt.Printf("Error initially translating %v: %v\n", pair.Name, err)
+            continue
+        }
+        if err := cache.stage(pair.Src, pair.Dst); err != nil {
+            fmt.Printf("Error updating cache for %v: %v\n", pair.Name, err)
+            continue
+        }
+        cacheDirty = true
     }
-
-    err = filepath.Walk(gmScriptsDir, f)
-    if err != nil {
-        fmt.Printf("Error during initial copying of scripts: %v\n", err)
-        return
+    // One Save for the whole scan instead of one per resource.
+    if cacheDirty {
+        if err := cache.Save(); err != nil {
+            fmt.Printf("Error saving cache: %v\n", err)
+        }
     }
 
     // Start monitoring files for changes
@@ -159,42 +202,58 @@ func main () {
     }
     defer watcher.Close()
 
+    batcher := newEventBatcher(func (batch map[string]fsnotify.Event) {
+        for _, event := range batch {
+            processWatcherEvent(watcher, event)
+        }
+    })
+
     // Watcher must be in a separate goroutine
     go func () {
         for {
             select {
             case event := <-watcher.Events:
-                processWatcherEvent(event)
+                batcher.add(event)
             case err := <-watcher.Errors:
                 fmt.Printf("Fsnotify watcher error: %v\n", err)
             }
         }
     }()
 
-    if err := watcher.Add(humanDir); err != nil {
+    if err := addRecursive(watcher, humanDir); err != nil {
         fmt.Printf("Error assigning human dir to fsnotify watcher: %v\n", err)
     }
-    if err := watcher.Add(gmObjectsDir); err != nil {
+    if err := addRecursive(watcher, gmObjectsDir); err != nil {
         fmt.Printf("Error assigning GM objects dir to fsnotify watcher: %v\n",
                 err)
     }
-    if err := watcher.Add(gmScriptsDir); err != nil {
+    if err := addRecursive(watcher, gmScriptsDir); err != nil {
         fmt.Printf("Error assigning GM scripts dir to fsnotify watcher: %v\n",
                 err)
     }
 
-    // Listen for typed commands on Stdin
+    // Start the HTTP/WebSocket control server, if requested.
+
+    if listenAddr != "" {
+        go serveControl(listenAddr)
+    }
+
+    // Listen for typed commands on Stdin. Runs through the same
+    // dispatchCommand used by the control server's /command endpoint, so
+    // both interfaces stay in sync.
 
     go func () {
         scan := bufio.NewScanner(os.Stdin)
         for scan.Scan() {
             text := scan.Text()
-            if text == "help" {
-                fmt.Println(helpMessage)
-            } else if text == "objects" {
-                fmt.Println(objectsHelpMessage)
-            } else if text == "events" {
-                fmt.Println(eventsHelpMessage())
+            if out, ok := dispatchCommand(text); ok {
+                fmt.Println(out)
+            } else if text == "rebuild" {
+                if err := cache.Wipe(); err != nil {
+                    fmt.Printf("Error wiping cache: %v\n", err)
+                } else {
+                    fmt.Println("Cache wiped")
+                }
             }
         }
     }()
@@ -215,62 +274,56 @@ func main () {
     fmt.Println("Success")
 }
 
-func processWatcherEvent (event fsnotify.Event) {
-    if event.Op == fsnotify.Write {
-        ext := filepath.Ext(event.Name)
-        isHuman := strings.HasPrefix(event.Name, humanDir)
-        isHumanObj := isHuman && ext == ".gmo"
-        isHumanScript := isHuman && ext == ".gml"
-        isGMObj := strings.HasPrefix(event.Name, gmObjectsDir) &&
-                ext == ".gmx" // close enough to ".object.gmx"
-        isGMScript := strings.HasPrefix(event.Name, gmScriptsDir) &&
-                ext == ".gml"
-
-        if isHumanObj {
-            if humanFileTimingOk(event.Name) {
-                humanChanged = time.Now()
-                lastHumanFileChanged = event.Name
-                translateHumanObject(event.Name)
-            }
-        } else if isHumanScript {
-            if humanFileTimingOk(event.Name) {
-                humanChanged = time.Now()
-                lastHumanFileChanged = event.Name
-                copyHumanScript(event.Name)
-            }
-        } else if isGMObj {
-            if gmFileTimingOk(event.Name) {
-                gmChanged = time.Now()
-                lastGMFileChanged = event.Name
-                translateGMObject(event.Name)
-            }
-        } else if isGMScript {
-            if gmFileTimingOk(event.Name) {
-                gmChanged = time.Now()
-                lastGMFileChanged = event.Name
-                copyGMScript(event.Name)
+func processWatcherEvent (watcher *fsnotify.Watcher, event fsnotify.Event) {
+    if event.Op&fsnotify.Create != 0 {
+        if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+            if err := addRecursive(watcher, event.Name); err != nil {
+                fmt.Printf("Error watching new directory %v: %v\n",
+                        event.Name, err)
             }
         }
+        return
     }
-}
 
-func humanFileTimingOk (humanFile string) bool {
-    return time.Since(gmChanged) > reverbSpacing &&
-            (lastHumanFileChanged != humanFile ||
-            time.Since(humanChanged) > dedupSpacing)
-}
+    if event.Op&fsnotify.Write == 0 {
+        return
+    }
 
-func gmFileTimingOk (gmFile string) bool {
-    return time.Since(humanChanged) > reverbSpacing &&
-            (lastGMFileChanged != gmFile ||
-            time.Since(gmChanged) > dedupSpacing)
+    if isIgnored(event.Name) {
+        logger.Debug("Suppressed %v (ignored by %v)", event.Name, configFileName)
+        return
+    }
+    if wasSelfWritten(event.Name) {
+        logger.Debug("Suppressed %v (written by our own last translation)", event.Name)
+        return
+    }
+
+    side, kind, ok := activeTranslator.ClassifyEvent(event.Name)
+    if !ok {
+        return
+    }
+
+    if side == SideHuman && kind == "object" {
+        translateHumanObject(event.Name)
+    } else if side == SideHuman && kind == "script" {
+        copyHumanScript(event.Name)
+    } else if side == SideGM && kind == "object" {
+        translateGMObject(event.Name)
+    } else if side == SideGM && kind == "script" {
+        copyGMScript(event.Name)
+    }
 }
 
 func copyHumanScript (humanScriptPath string) {
     fn := filepath.Base(humanScriptPath)
-    gmScriptPath := filepath.Join(gmScriptsDir, fn)
     scriptName := strings.Split(fn, ".")[0]
 
+    gmScriptPath, ok := activeTranslator.GMPathFor(humanScriptPath)
+    if !ok {
+        logger.Error("No GM-side path for %v", humanScriptPath)
+        return
+    }
+
     // GM file not existing before translation meanse we have to add it to the
     // project file
 
@@ -279,86 +332,134 @@ func copyHumanScript (humanScriptPath string) {
 
     // Copy script
 
-    err = cp(gmScriptPath, humanScriptPath)
+    err = activeTranslator.HumanToGM(humanScriptPath, gmScriptPath)
     if err != nil {
-        fmt.Printf("[%v] %v\n", time.Now().Format("15:04:05"), err)
+        logger.Error("%v", err)
+        publishEvent(Event{Direction: "human_to_gm", Kind: "script",
+                Name: scriptName, Error: err.Error()})
     } else {
-        fmt.Printf("[%v] Copied %v\n",
-                time.Now().Format("15:04:05"), scriptName)
+        markSelfWritten(gmScriptPath)
+        logger.Info("Copied %v", scriptName)
+        publishEvent(Event{Direction: "human_to_gm", Kind: "script",
+                Name: scriptName})
     }
 
     // If necessary, add to project file
 
     if !gmFileExisted {
-        err = AppendResourceToGMProject(fn, "script", "scripts")
+        err = activeTranslator.AppendResource(fn, "script", "scripts")
         if err != nil {
-            fmt.Printf("[%v] %v\n", time.Now().Format("15:04:05"), err)
+            logger.Error("%v", err)
         } else {
-            fmt.Printf("[%v] Project file updated %v\n",
-                    time.Now().Format("15:04:05"), scriptName)
+            logger.Info("Project file updated %v", scriptName)
         }
     }
 }
 
 func copyGMScript (gmScriptPath string) {
     fn := filepath.Base(gmScriptPath)
-    humanScriptPath := filepath.Join(humanDir, fn)
-    err := cp(humanScriptPath, gmScriptPath)
+    scriptName := strings.Split(fn, ".")[0]
+
+    humanScriptPath, ok := activeTranslator.HumanPathFor(gmScriptPath)
+    if !ok {
+        logger.Error("No human-side path for %v", gmScriptPath)
+        return
+    }
+
+    err := activeTranslator.GMToHuman(gmScriptPath, humanScriptPath)
     if err != nil {
-        fmt.Printf("[%v] (From GM) %v\n", time.Now().Format("15:04:05"), err)
+        logger.Error("(From GM) %v", err)
+        publishEvent(Event{Direction: "gm_to_human", Kind: "script",
+                Name: scriptName, Error: err.Error()})
     } else {
-        scriptName := strings.Split(fn, ".")[0]
-        fmt.Printf("[%v] (From GM) Copied %v\n",
-                time.Now().Format("15:04:05"), scriptName)
+        markSelfWritten(humanScriptPath)
+        logger.Info("(From GM) Copied %v", scriptName)
+        publishEvent(Event{Direction: "gm_to_human", Kind: "script",
+                Name: scriptName})
     }
 }
 
 func translateHumanObject (humanObjPath string) {
     objName := strings.Split(filepath.Base(humanObjPath), ".")[0]
-    gmObjPath := filepath.Join(gmObjectsDir, objName + ".object.gmx")
+
+    gmObjPath, ok := activeTranslator.GMPathFor(humanObjPath)
+    if !ok {
+        logger.Error("No GM-side path for %v", humanObjPath)
+        return
+    }
+
+    // The cache catches the common fsnotify false positive where an
+    // editor rewrites a file with identical bytes.
+    if cache.UpToDate(humanObjPath, gmObjPath) {
+        return
+    }
 
     // GM file not existing before translation meanse we have to add it to the
     // project file
 
     _, err := os.Stat(gmObjPath)
-    gmObjFileExisted := !os.IsNotExist(err) 
+    gmObjFileExisted := !os.IsNotExist(err)
 
     // Translate object
 
-    err = HumanObjectFileToGMObjectFile(humanObjPath, gmObjPath)
+    err = activeTranslator.HumanToGM(humanObjPath, gmObjPath)
     if err != nil {
-        fmt.Printf("[%v] %v\n", time.Now().Format("15:04:05"), err)
+        logger.Error("%v", err)
+        publishEvent(Event{Direction: "human_to_gm", Kind: "object",
+                Name: objName, Error: err.Error()})
     } else {
-        fmt.Printf("[%v] Translated %v\n", time.Now().Format("15:04:05"),
-                objName)
+        markSelfWritten(gmObjPath)
+        if err := cache.Record(humanObjPath, gmObjPath); err != nil {
+            logger.Error("Error updating cache for %v: %v", objName, err)
+        }
+        logger.Info("Translated %v", objName)
+        publishEvent(Event{Direction: "human_to_gm", Kind: "object",
+                Name: objName})
         // Touching the project file causes GM:Studio to close all
         // folders, which is annoying.
         //touchProjectFile()
+        runScriptHooks("human_write", objName)
     }
 
     // If necessary, add to project file
 
     if !gmObjFileExisted {
-        err = AppendResourceToGMProject(objName, "object", "objects")
+        err = activeTranslator.AppendResource(objName, "object", "objects")
         if err != nil {
-            fmt.Printf("[%v] %v\n", time.Now().Format("15:04:05"), err)
+            logger.Error("%v", err)
         } else {
-            fmt.Printf("[%v] Project file updated %v\n",
-                    time.Now().Format("15:04:05"), objName)
+            logger.Info("Project file updated %v", objName)
         }
     }
 }
 
 func translateGMObject (gmObjPath string) {
     objName := strings.Split(filepath.Base(gmObjPath), ".")[0]
-    humanObjPath := filepath.Join(humanDir, objName + ".gmo")
 
-    err := GMObjectFileToHumanObjectFile(gmObjPath, humanObjPath)
+    humanObjPath, ok := activeTranslator.HumanPathFor(gmObjPath)
+    if !ok {
+        logger.Error("No human-side path for %v", gmObjPath)
+        return
+    }
+
+    if cache.UpToDate(gmObjPath, humanObjPath) {
+        return
+    }
+
+    err := activeTranslator.GMToHuman(gmObjPath, humanObjPath)
     if err != nil {
-        fmt.Printf("[%v] (From GM) %v\n", time.Now().Format("15:04:05"), err)
+        logger.Error("(From GM) %v", err)
+        publishEvent(Event{Direction: "gm_to_human", Kind: "object",
+                Name: objName, Error: err.Error()})
     } else {
-        fmt.Printf("[%v] (From GM) Translated %v\n",
-                time.Now().Format("15:04:05"), objName)
+        markSelfWritten(humanObjPath)
+        if err := cache.Record(gmObjPath, humanObjPath); err != nil {
+            logger.Error("Error updating cache for %v: %v", objName, err)
+        }
+        logger.Info("(From GM) Translated %v", objName)
+        publishEvent(Event{Direction: "gm_to_human", Kind: "object",
+                Name: objName})
+        runScriptHooks("gm_write", objName)
     }
 }
 
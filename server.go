@@ -0,0 +1,180 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+
+    "github.com/gorilla/websocket"
+)
+
+// dispatchCommand is the single place that knows what "help", "objects",
+// and "events" mean, so the stdin command loop and the control server's
+// /command endpoint can't drift out of sync.
+func dispatchCommand (text string) (string, bool) {
+    switch text {
+    case "help":
+        return helpMessage, true
+    case "objects":
+        return objectsHelpMessage, true
+    case "events":
+        return eventsHelpMessage(), true
+    }
+    return "", false
+}
+
+var wsUpgrader = websocket.Upgrader{
+    ReadBufferSize:  1024,
+    WriteBufferSize: 1024,
+}
+
+// serveControl starts the opt-in HTTP/WebSocket control surface that lets
+// an editor plugin see translation status and drive a resync, instead of
+// watching the terminal.
+func serveControl (addr string) {
+    addr = normalizeListenAddr(addr)
+
+    mux := http.NewServeMux()
+
+    mux.HandleFunc("/resources", handleResources)
+    mux.HandleFunc("/translate/", handleTranslate)
+    mux.HandleFunc("/rescan", handleRescan)
+    mux.HandleFunc("/events", handleEventsWS)
+    mux.HandleFunc("/command/", handleCommand)
+
+    fmt.Printf("Control server listening on %v\n", addr)
+    if err := http.ListenAndServe(addr, mux); err != nil {
+        fmt.Printf("Control server error: %v\n", err)
+    }
+}
+
+// normalizeListenAddr turns a bare ":port" address into "127.0.0.1:port".
+// /translate and /rescan let anyone who can reach the listener force
+// arbitrary resource translation or resync, so a bare port shouldn't
+// default to binding every interface on the machine -- callers who really
+// want that can still pass an explicit "0.0.0.0:port".
+func normalizeListenAddr (addr string) string {
+    if strings.HasPrefix(addr, ":") {
+        return "127.0.0.1" + addr
+    }
+    return addr
+}
+
+func handleResources (w http.ResponseWriter, r *http.Request) {
+    pairs, err := activeTranslator.InitialScan(projectDir)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    json.NewEncoder(w).Encode(pairs)
+}
+
+// handleTranslate re-translates the single named resource from its GM
+// source, e.g. to force a resync after a Git checkout.
+func handleTranslate (w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "POST required", http.StatusMethodNotAllowed)
+        return
+    }
+
+    name := strings.TrimPrefix(r.URL.Path, "/translate/")
+    pairs, err := activeTranslator.InitialScan(projectDir)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    for _, pair := range pairs {
+        if pair.Name != name {
+            continue
+        }
+        if err := activeTranslator.GMToHuman(pair.Src, pair.Dst); err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        if err := cache.Record(pair.Src, pair.Dst); err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        publishEvent(Event{Direction: "gm_to_human", Kind: pair.Kind, Name: name})
+        w.WriteHeader(http.StatusOK)
+        return
+    }
+
+    http.Error(w, "resource not found", http.StatusNotFound)
+}
+
+// handleRescan redoes the full initial scan, translating anything the
+// cache considers stale. Useful after a Git checkout touches many files
+// at once.
+func handleRescan (w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "POST required", http.StatusMethodNotAllowed)
+        return
+    }
+
+    pairs, err := activeTranslator.InitialScan(projectDir)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    cacheDirty := false
+    for _, pair := range pairs {
+        if cache.UpToDate(pair.Src, pair.Dst) {
+            continue
+        }
+        if err := activeTranslator.GMToHuman(pair.Src, pair.Dst); err != nil {
+            publishEvent(Event{Direction: "gm_to_human", Kind: pair.Kind,
+                    Name: pair.Name, Error: err.Error()})
+            continue
+        }
+        if err := cache.stage(pair.Src, pair.Dst); err != nil {
+            publishEvent(Event{Direction: "gm_to_human", Kind: pair.Kind,
+                    Name: pair.Name, Error: err.Error()})
+            continue
+        }
+        cacheDirty = true
+        publishEvent(Event{Direction: "gm_to_human", Kind: pair.Kind, Name: pair.Name})
+    }
+    // One Save for the whole rescan instead of one per resource -- see
+    // cache.go's Record/stage comment.
+    if cacheDirty {
+        if err := cache.Save(); err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+    }
+
+    w.WriteHeader(http.StatusOK)
+}
+
+// handleEventsWS streams every published Event as a JSON message, so an
+// editor plugin can show translation status/errors inline.
+func handleEventsWS (w http.ResponseWriter, r *http.Request) {
+    conn, err := wsUpgrader.Upgrade(w, r, nil)
+    if err != nil {
+        return
+    }
+    defer conn.Close()
+
+    ch := subscribeEvents()
+    defer unsubscribeEvents(ch)
+
+    for event := range ch {
+        if err := conn.WriteJSON(event); err != nil {
+            return
+        }
+    }
+}
+
+func handleCommand (w http.ResponseWriter, r *http.Request) {
+    name := strings.TrimPrefix(r.URL.Path, "/command/")
+    out, ok := dispatchCommand(name)
+    if !ok {
+        http.Error(w, "unknown command", http.StatusNotFound)
+        return
+    }
+    w.Write([]byte(out))
+}
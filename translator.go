@@ -0,0 +1,70 @@
+package main
+
+import "path/filepath"
+
+// ResourcePair is one resource's GM-side file and its translated
+// human-side counterpart, as produced by a Translator's initial scan.
+type ResourcePair struct {
+    Name string
+    Src  string
+    Dst  string
+    Kind string // "object" or "script"
+}
+
+// Side identifies which half of a sync a watched file belongs to.
+type Side int
+
+const (
+    SideGM Side = iota
+    SideHuman
+)
+
+// Translator lets the watcher loop and reverb/dedupe logic serve more
+// than one GameMaker project format. GMS1.4's .object.gmx/.gml/.gmo
+// layout is the original (and so far only complete) implementation;
+// GMS2's .yy format is a second backend.
+type Translator interface {
+    // InitialScan walks projectDir and returns every resource that
+    // should be translated/copied before the watcher starts.
+    InitialScan (projectDir string) ([]ResourcePair, error)
+
+    // GMToHuman translates/copies a GM-side resource file into its
+    // human-readable counterpart.
+    GMToHuman (src string, dst string) error
+
+    // HumanToGM translates/copies a human-readable resource file back
+    // into its GM-side counterpart.
+    HumanToGM (src string, dst string) error
+
+    // ClassifyEvent reports which side path belongs to and what kind of
+    // resource it is, or ok=false if this translator doesn't recognize
+    // it (e.g. an unrelated file under the watched directories).
+    ClassifyEvent (path string) (side Side, kind string, ok bool)
+
+    // GMPathFor returns the GM-side path paired with humanPath, or
+    // ok=false if humanPath isn't a resource this translator recognizes.
+    // Used instead of hardcoding a GMS1.4-shaped path in main.go, since
+    // each backend has its own naming convention (e.g. GMS2 nests each
+    // object's .yy file under objects/<name>/).
+    GMPathFor (humanPath string) (path string, ok bool)
+
+    // HumanPathFor returns the human-side path paired with gmPath, or
+    // ok=false if gmPath isn't a resource this translator recognizes.
+    HumanPathFor (gmPath string) (path string, ok bool)
+
+    // AppendResource registers a newly-created resource with the GM
+    // project, if this backend's project format needs that (GMS1.4's
+    // .project.gmx does; GMS2's .yyp resource tree does not yet, so
+    // gms2Translator no-ops here).
+    AppendResource (name string, kind string, dir string) error
+}
+
+// DetectTranslator picks a Translator backend by sniffing projectPath's
+// extension: ".yyp" is a GameMaker Studio 2 project, anything else
+// (namely ".project.gmx") is GMS1.4.
+func DetectTranslator (projectPath string) Translator {
+    if filepath.Ext(projectPath) == ".yyp" {
+        return &gms2Translator{}
+    }
+    return &gms1Translator{}
+}
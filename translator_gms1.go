@@ -0,0 +1,126 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// gms1Translator is the original NiceObjects backend: GameMaker Studio
+// 1.4's objects/*.object.gmx and scripts/*.gml, translated to/from a
+// gm_txt/ folder of .gmo and .gml files.
+type gms1Translator struct{}
+
+func (t *gms1Translator) InitialScan (projectDir string) ([]ResourcePair, error) {
+    objectsDir := filepath.Join(projectDir, "objects")
+    scriptsDir := filepath.Join(projectDir, "scripts")
+    humanDir   := filepath.Join(projectDir, "gm_txt")
+
+    var pairs []ResourcePair
+
+    err := filepath.Walk(objectsDir, func (path string, info os.FileInfo, err error) error {
+        if err != nil || info.IsDir() || !strings.HasSuffix(path, ".object.gmx") {
+            return err
+        }
+        if isIgnored(path) {
+            return nil
+        }
+        name := strings.TrimSuffix(filepath.Base(path), ".object.gmx")
+        pairs = append(pairs, ResourcePair{
+            Name: name,
+            Src:  path,
+            Dst:  filepath.Join(humanDir, name+".gmo"),
+            Kind: "object",
+        })
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    err = filepath.Walk(scriptsDir, func (path string, info os.FileInfo, err error) error {
+        if err != nil || info.IsDir() || filepath.Ext(path) != ".gml" {
+            return err
+        }
+        if isIgnored(path) {
+            return nil
+        }
+        pairs = append(pairs, ResourcePair{
+            Name: strings.TrimSuffix(filepath.Base(path), ".gml"),
+            Src:  path,
+            Dst:  filepath.Join(humanDir, filepath.Base(path)),
+            Kind: "script",
+        })
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    return pairs, nil
+}
+
+func (t *gms1Translator) GMToHuman (src string, dst string) error {
+    if strings.HasSuffix(src, ".object.gmx") {
+        return GMObjectFileToHumanObjectFile(src, dst)
+    }
+    return cp(dst, src)
+}
+
+func (t *gms1Translator) HumanToGM (src string, dst string) error {
+    if strings.HasSuffix(dst, ".object.gmx") {
+        return HumanObjectFileToGMObjectFile(src, dst)
+    }
+    return cp(dst, src)
+}
+
+func (t *gms1Translator) ClassifyEvent (path string) (Side, string, bool) {
+    ext := filepath.Ext(path)
+    isHuman := strings.HasPrefix(path, humanDir)
+
+    if isHuman && ext == ".gmo" {
+        return SideHuman, "object", true
+    }
+    if isHuman && (ext == ".gml" || isIncludedExt(ext)) {
+        return SideHuman, "script", true
+    }
+    if strings.HasPrefix(path, gmObjectsDir) && ext == ".gmx" {
+        return SideGM, "object", true // close enough to ".object.gmx"
+    }
+    if strings.HasPrefix(path, gmScriptsDir) && (ext == ".gml" || isIncludedExt(ext)) {
+        return SideGM, "script", true
+    }
+
+    return SideGM, "", false
+}
+
+func (t *gms1Translator) GMPathFor (humanPath string) (string, bool) {
+    ext := filepath.Ext(humanPath)
+    switch ext {
+    case ".gmo":
+        name := strings.TrimSuffix(filepath.Base(humanPath), ext)
+        return filepath.Join(gmObjectsDir, name+".object.gmx"), true
+    case ".gml":
+        return filepath.Join(gmScriptsDir, filepath.Base(humanPath)), true
+    }
+    if isIncludedExt(ext) {
+        return filepath.Join(gmScriptsDir, filepath.Base(humanPath)), true
+    }
+    return "", false
+}
+
+func (t *gms1Translator) AppendResource (name string, kind string, dir string) error {
+    return AppendResourceToGMProject(name, kind, dir)
+}
+
+func (t *gms1Translator) HumanPathFor (gmPath string) (string, bool) {
+    if strings.HasSuffix(gmPath, ".object.gmx") {
+        name := strings.TrimSuffix(filepath.Base(gmPath), ".object.gmx")
+        return filepath.Join(humanDir, name+".gmo"), true
+    }
+    ext := filepath.Ext(gmPath)
+    if ext == ".gml" || isIncludedExt(ext) {
+        return filepath.Join(humanDir, filepath.Base(gmPath)), true
+    }
+    return "", false
+}
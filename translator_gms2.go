@@ -0,0 +1,113 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// gms2Translator is a first-pass backend for GameMaker Studio 2 projects
+// (.yyp). GMS2 objects are already JSON (.yy), so there's no custom
+// format to parse the way GMS1.4's .object.gmx requires -- for now this
+// backend just pretty-prints the JSON both ways, which is enough to make
+// it diffable and human-editable. A real resource model (events, parent/
+// sprite references broken out into their own human-friendly sections)
+// is left for a follow-up.
+type gms2Translator struct{}
+
+func (t *gms2Translator) InitialScan (projectDir string) ([]ResourcePair, error) {
+    objectsDir := filepath.Join(projectDir, "objects")
+    humanDir   := filepath.Join(projectDir, "gm_txt")
+
+    var pairs []ResourcePair
+
+    err := filepath.Walk(objectsDir, func (path string, info os.FileInfo, err error) error {
+        if err != nil || info.IsDir() || filepath.Ext(path) != ".yy" {
+            return err
+        }
+        if isIgnored(path) {
+            return nil
+        }
+        name := strings.TrimSuffix(filepath.Base(path), ".yy")
+        pairs = append(pairs, ResourcePair{
+            Name: name,
+            Src:  path,
+            Dst:  filepath.Join(humanDir, name+".gmo"),
+            Kind: "object",
+        })
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    return pairs, nil
+}
+
+func (t *gms2Translator) GMToHuman (src string, dst string) error {
+    return t.reformat(src, dst)
+}
+
+func (t *gms2Translator) HumanToGM (src string, dst string) error {
+    return t.reformat(src, dst)
+}
+
+func (t *gms2Translator) reformat (src string, dst string) error {
+    data, err := ioutil.ReadFile(src)
+    if err != nil {
+        return err
+    }
+
+    var v interface{}
+    if err := json.Unmarshal(data, &v); err != nil {
+        return err
+    }
+
+    var out bytes.Buffer
+    enc := json.NewEncoder(&out)
+    enc.SetIndent("", "    ")
+    if err := enc.Encode(v); err != nil {
+        return err
+    }
+
+    return ioutil.WriteFile(dst, out.Bytes(), os.ModePerm)
+}
+
+func (t *gms2Translator) ClassifyEvent (path string) (Side, string, bool) {
+    ext := filepath.Ext(path)
+    isHuman := strings.HasPrefix(path, humanDir)
+
+    if isHuman && ext == ".gmo" {
+        return SideHuman, "object", true
+    }
+    if strings.HasPrefix(path, gmObjectsDir) && ext == ".yy" {
+        return SideGM, "object", true
+    }
+
+    return SideGM, "", false
+}
+
+func (t *gms2Translator) GMPathFor (humanPath string) (string, bool) {
+    if filepath.Ext(humanPath) != ".gmo" {
+        return "", false
+    }
+    name := strings.TrimSuffix(filepath.Base(humanPath), ".gmo")
+    return filepath.Join(gmObjectsDir, name, name+".yy"), true
+}
+
+func (t *gms2Translator) AppendResource (name string, kind string, dir string) error {
+    // The .yyp resource tree isn't modeled yet, so there's nothing to
+    // register a newly-created resource into.
+    return nil
+}
+
+func (t *gms2Translator) HumanPathFor (gmPath string) (string, bool) {
+    if filepath.Ext(gmPath) != ".yy" {
+        return "", false
+    }
+    name := strings.TrimSuffix(filepath.Base(gmPath), ".yy")
+    return filepath.Join(humanDir, name+".gmo"), true
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "sync"
+    "time"
+
+    "github.com/fsnotify/fsnotify"
+)
+
+// defaultQuietPeriod is how long the watcher waits for events to stop
+// arriving before flushing a batch, absent a niceobjects.yaml override.
+// Replaces the old reverbSpacing/dedupSpacing globals, which tried to
+// approximate the same thing with two racing timestamps.
+const defaultQuietPeriod time.Duration = 150 * time.Millisecond
+
+// quietPeriod is set from defaultQuietPeriod, then overridden in main()
+// if niceobjects.yaml sets quiet_period_ms.
+var quietPeriod time.Duration = defaultQuietPeriod
+
+// applyQuietPeriodOverride sets quietPeriod from cfg.QuietPeriodMS, if the
+// config set one, leaving the default untouched otherwise.
+func applyQuietPeriodOverride (cfg Config) {
+    if cfg.QuietPeriodMS > 0 {
+        quietPeriod = time.Duration(cfg.QuietPeriodMS) * time.Millisecond
+    }
+}
+
+// addRecursive walks dir, adding it and every subdirectory to watcher, so
+// nested resource groups (GMS supports them under objects/ and scripts/)
+// are watched from the start.
+func addRecursive (watcher *fsnotify.Watcher, dir string) error {
+    return filepath.Walk(dir, func (path string, info os.FileInfo, err error) error {
+        if err != nil {
+            return err
+        }
+        if info.IsDir() {
+            // The root itself is always watched; niceobjects.yaml's
+            // include/ignore lists only apply to subdirectories.
+            if path != dir && isIgnoredDir(path) {
+                return filepath.SkipDir
+            }
+            return watcher.Add(path)
+        }
+        return nil
+    })
+}
+
+// eventBatcher coalesces a burst of fsnotify events into a single flush,
+// so a mass save of many objects results in one batched translation pass
+// rather than racing goroutines stomping on each other.
+type eventBatcher struct {
+    mu      sync.Mutex
+    pending map[string]fsnotify.Event
+    timer   *time.Timer
+    flush   func (map[string]fsnotify.Event)
+}
+
+func newEventBatcher (flush func (map[string]fsnotify.Event)) *eventBatcher {
+    return &eventBatcher{
+        pending: make(map[string]fsnotify.Event),
+        flush:   flush,
+    }
+}
+
+// add records event and (re)starts the quiet-period timer. Repeated
+// events for the same path simply overwrite each other in the pending
+// map, so only the latest is flushed.
+func (b *eventBatcher) add (event fsnotify.Event) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    b.pending[event.Name] = event
+
+    if b.timer != nil {
+        b.timer.Stop()
+    }
+    b.timer = time.AfterFunc(quietPeriod, b.fire)
+}
+
+func (b *eventBatcher) fire () {
+    b.mu.Lock()
+    batch := b.pending
+    b.pending = make(map[string]fsnotify.Event)
+    b.mu.Unlock()
+
+    if len(batch) > 0 {
+        b.flush(batch)
+    }
+}
+
+// selfWritten tracks files we just translated/copied ourselves, so the
+// fsnotify event our own write produces doesn't bounce straight back into
+// another translation (the "reverb" problem).
+var selfWritten = struct {
+    mu sync.Mutex
+    m  map[string]time.Time
+}{m: make(map[string]time.Time)}
+
+func markSelfWritten (path string) {
+    selfWritten.mu.Lock()
+    selfWritten.m[path] = time.Now()
+    selfWritten.mu.Unlock()
+}
+
+func wasSelfWritten (path string) bool {
+    selfWritten.mu.Lock()
+    defer selfWritten.mu.Unlock()
+
+    t, ok := selfWritten.m[path]
+    if !ok {
+        return false
+    }
+    delete(selfWritten.m, path)
+    return time.Since(t) < quietPeriod*2
+}
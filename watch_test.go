@@ -0,0 +1,61 @@
+package main
+
+import (
+    "testing"
+    "time"
+
+    "github.com/fsnotify/fsnotify"
+)
+
+func TestEventBatcherCoalescesBurst (t *testing.T) {
+    oldQuietPeriod := quietPeriod
+    quietPeriod = 20 * time.Millisecond
+    defer func () { quietPeriod = oldQuietPeriod }()
+
+    flushes := make(chan map[string]fsnotify.Event, 8)
+    b := newEventBatcher(func (batch map[string]fsnotify.Event) {
+        flushes <- batch
+    })
+
+    for i := 0; i < 50; i++ {
+        b.add(fsnotify.Event{Name: "/proj/objects/oPlayer.object.gmx", Op: fsnotify.Write})
+    }
+
+    select {
+    case batch := <-flushes:
+        if len(batch) != 1 {
+            t.Errorf("expected 1 coalesced entry, got %v", len(batch))
+        }
+    case <-time.After(time.Second):
+        t.Fatal("batch never flushed")
+    }
+
+    select {
+    case <-flushes:
+        t.Error("expected only one flush for a single coalesced burst")
+    case <-time.After(100 * time.Millisecond):
+    }
+}
+
+func TestQuietPeriodOverrideFromConfig (t *testing.T) {
+    oldQuietPeriod := quietPeriod
+    defer func () { quietPeriod = oldQuietPeriod }()
+
+    applyQuietPeriodOverride(Config{QuietPeriodMS: 500})
+
+    if quietPeriod != 500*time.Millisecond {
+        t.Errorf("expected quietPeriod to be overridden to 500ms, got %v", quietPeriod)
+    }
+}
+
+func TestQuietPeriodOverrideLeavesDefaultWhenUnset (t *testing.T) {
+    oldQuietPeriod := quietPeriod
+    quietPeriod = defaultQuietPeriod
+    defer func () { quietPeriod = oldQuietPeriod }()
+
+    applyQuietPeriodOverride(Config{})
+
+    if quietPeriod != defaultQuietPeriod {
+        t.Errorf("expected quietPeriod to stay at the default, got %v", quietPeriod)
+    }
+}